@@ -0,0 +1,260 @@
+package filedb
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rogpeppe/go-internal/lockedfile"
+)
+
+// Batch stages a set of per-key mutations to be committed atomically by
+// DB.Write. DB.Write reads every staged key, locks them all and re-checks
+// their versions: either every key staged in the batch is updated, or, if
+// any of them was concurrently modified in between, none of them are. A
+// change made to a key after it was staged but before DB.Write reads it is
+// not detected; DB.Write applies the staged operation on top of it.
+type Batch struct {
+	ops map[string]func(existing any) (any, error)
+}
+
+// NewBatch returns an empty Batch.
+func NewBatch() *Batch {
+	return &Batch{ops: make(map[string]func(existing any) (any, error))}
+}
+
+// Set stages setting the document for key.
+func (b *Batch) Set(key string, doc any) {
+	b.ops[key] = func(_ any) (any, error) { return doc, nil }
+}
+
+// Delete stages deleting the document for key.
+func (b *Batch) Delete(key string) {
+	b.ops[key] = func(_ any) (any, error) { return nil, nil }
+}
+
+// Update stages an update to key, computed from its current document (or
+// nil if the key does not exist) by apply. apply is invoked when the batch
+// is committed by DB.Write, not when Update is called. Returning a nil
+// document deletes the key.
+func (b *Batch) Update(key string, apply func(existing any) (any, error)) {
+	b.ops[key] = apply
+}
+
+// Write commits every operation staged in b atomically.
+func (db *DB) Write(b *Batch) error {
+	return db.WriteWithRetry(b, 0)
+}
+
+// WriteWithRetry commits b like Write, retrying the whole batch up to the
+// given number of times if it collides with a concurrent writer. A
+// negative retries retries forever.
+func (db *DB) WriteWithRetry(b *Batch, retries int) error {
+
+	keys := make([]string, 0, len(b.ops))
+	for key := range b.ops {
+		keys = append(keys, key)
+	}
+	// Sort to establish a global lock order, so two batches that touch an
+	// overlapping set of keys can never deadlock against each other.
+	sort.Strings(keys)
+
+	do := func() error {
+
+		// Read the current state of every key and compute its new document
+		// before taking any locks.
+
+		olds := make(map[string]dbEntry, len(keys))
+		news := make(map[string]any, len(keys))
+		for _, key := range keys {
+			old, err := db.get(key)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			newDoc, err := b.ops[key](old.doc)
+			if err != nil {
+				return err
+			}
+			olds[key] = old
+			news[key] = newDoc
+		}
+
+		// Stage the new documents in tmp files next to their targets. Any
+		// tmp file left over by a failed or aborted commit is unlinked
+		// before returning.
+
+		tmps := make(map[string]string, len(keys))
+		defer func() {
+			for _, name := range tmps {
+				os.Remove(name)
+			}
+		}()
+		for _, key := range keys {
+			if news[key] == nil {
+				continue
+			}
+			f, err := tmpFile(db.root, key)
+			if err != nil {
+				return err
+			}
+			err = db.writeEntry(f, dbEntry{version: olds[key].version + 1, doc: news[key]})
+			f.Close()
+			if err != nil {
+				return err
+			}
+			tmps[key] = f.Name()
+		}
+
+		// Lock every key, in sorted order, so a concurrent batch or Update
+		// touching the same keys can't interleave with this commit.
+
+		var unlocks []func()
+		defer func() {
+			for i := len(unlocks) - 1; i >= 0; i-- {
+				unlocks[i]()
+			}
+		}()
+		for _, key := range keys {
+			unlock, err := lockedfile.MutexAt(lockPath(db.root, key)).Lock()
+			if err != nil {
+				return err
+			}
+			unlocks = append(unlocks, unlock)
+		}
+
+		// Now that every key is locked, re-check that none of them changed
+		// since we read it above. If any did, abort without modifying any
+		// target file; the caller can retry the whole batch.
+
+		for _, key := range keys {
+			neww, err := db.get(key)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if neww.version != olds[key].version {
+				return ErrConcurrentMod
+			}
+		}
+
+		// Back up every key that already exists before the commit loop
+		// touches it, by hard-linking it into a private directory (the same
+		// trick Snapshot uses), so a failure partway through the commit loop
+		// below can be rolled back instead of leaving a partial batch
+		// applied.
+
+		backupDir, err := os.MkdirTemp(filepath.Join(db.root, ".filedb"), "batch-backup-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(backupDir)
+
+		backups := make(map[string]string, len(keys))
+		for _, key := range keys {
+			if olds[key].version == 0 {
+				continue // did not exist before this batch; nothing to back up
+			}
+			dst := filepath.Join(backupDir, filepath.ToSlash(key))
+			if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+				return err
+			}
+			if err := os.Link(keyPath(db.root, key), dst); err != nil {
+				return err
+			}
+			backups[key] = dst
+		}
+
+		// Commit: record every mutation in the WAL, the same way Update does,
+		// immediately before the rename/remove it protects, and roll back
+		// every key already committed in this loop from its backup if a
+		// later one fails, so an error partway through doesn't leave the
+		// batch's target files partially updated. A crash partway through
+		// is recovered key by key: WAL recovery replays the records appended
+		// before the crash, and keys after that point keep their old
+		// documents. When the WAL is enabled,
+		// walMu is held across the whole loop, not just each append, so a
+		// concurrent Checkpoint can't truncate a record whose rename/remove
+		// hasn't happened yet.
+
+		var committed []string
+		rollback := func() {
+			for i := len(committed) - 1; i >= 0; i-- {
+				key := committed[i]
+				if backup, ok := backups[key]; ok {
+					os.Rename(backup, keyPath(db.root, key))
+				} else {
+					os.Remove(keyPath(db.root, key))
+				}
+			}
+		}
+
+		events := make([]Event, 0, len(keys))
+		commit := func() error {
+			for _, key := range keys {
+				if db.wal != nil {
+					rec := walRecord{Key: key, Version: olds[key].version + 1}
+					if news[key] == nil {
+						rec.Tomb = true
+					} else {
+						var buf bytes.Buffer
+						if err := db.codec.Encode(&buf, news[key]); err != nil {
+							return err
+						}
+						rec.Doc = buf.Bytes()
+					}
+					if err := appendWAL(db.wal, rec); err != nil {
+						return err
+					}
+				}
+
+				if news[key] == nil {
+					if err := os.Remove(keyPath(db.root, key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+						return err
+					}
+					committed = append(committed, key)
+					events = append(events, Event{Key: key, OldVersion: olds[key].version, Kind: EventDelete})
+					continue
+				}
+				if err := os.Rename(tmps[key], keyPath(db.root, key)); err != nil {
+					return err
+				}
+				delete(tmps, key)
+				committed = append(committed, key)
+				events = append(events, Event{Key: key, OldVersion: olds[key].version, NewVersion: olds[key].version + 1, Kind: EventPut})
+			}
+			return nil
+		}
+
+		if db.wal != nil {
+			db.walMu.Lock()
+		}
+		commitErr := commit()
+		if db.wal != nil {
+			db.walMu.Unlock()
+		}
+		if commitErr != nil {
+			rollback()
+			return commitErr
+		}
+
+		for _, ev := range events {
+			db.notify(ev)
+		}
+
+		return nil
+	}
+
+	for i := 0; retries < 0 || i <= retries; i++ {
+		err := do()
+		if err == nil || err != ErrConcurrentMod {
+			return err
+		}
+		rnd := rand.Intn(50) + 50
+		time.Sleep(time.Duration(rnd) * time.Millisecond)
+	}
+
+	return ErrConcurrentMod
+}
@@ -0,0 +1,67 @@
+package filedb
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func init() {
+	// Register the dynamic types that Get, List and Iterator documents
+	// decode into by default, so GobCodec can round-trip them through
+	// dbEntryWire's Doc any field.
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+	gob.Register("")
+	gob.Register(float64(0))
+	gob.Register(true)
+	gob.Register([]byte(nil))
+}
+
+// Codec encodes and decodes the on-disk representation of a document.
+// Open defaults to JSONCodec; pass a different one with WithCodec.
+type Codec interface {
+	// Name identifies the codec in .filedb's metadata. Open refuses to
+	// open a store whose metadata names a different codec, so documents
+	// already on disk never get silently misread.
+	Name() string
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+// JSONCodec encodes documents as JSON. It's the default.
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string                    { return "json" }
+func (JSONCodec) Encode(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+func (JSONCodec) Decode(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) }
+
+// GobCodec encodes documents with encoding/gob. Beyond the handful of
+// primitive/generic shapes registered by default (the ones Get, List and
+// Iterator decode untyped documents into, plus []byte), storing a custom
+// struct with GobCodec requires registering it first with
+// RegisterGobType, since gob requires every concrete type crossing an
+// interface boundary to be registered before it's encoded.
+type GobCodec struct{}
+
+func (GobCodec) Name() string                    { return "gob" }
+func (GobCodec) Encode(w io.Writer, v any) error { return gob.NewEncoder(w).Encode(v) }
+func (GobCodec) Decode(r io.Reader, v any) error { return gob.NewDecoder(r).Decode(v) }
+
+// RegisterGobType registers a concrete document type with encoding/gob so
+// GobCodec can store it through DB's any-typed Get/Set/Update API. Call it
+// once at startup for any custom struct passed to Set/Update under
+// WithCodec(GobCodec{}); JSONCodec and CBORCodec need no such step.
+func RegisterGobType(v any) {
+	gob.Register(v)
+}
+
+// CBORCodec encodes documents as CBOR (RFC 8949). It's more compact than
+// JSON for documents holding large binary blobs.
+type CBORCodec struct{}
+
+func (CBORCodec) Name() string                    { return "cbor" }
+func (CBORCodec) Encode(w io.Writer, v any) error { return cbor.NewEncoder(w).Encode(v) }
+func (CBORCodec) Decode(r io.Reader, v any) error { return cbor.NewDecoder(r).Decode(v) }
@@ -0,0 +1,102 @@
+package filedb
+
+import "testing"
+
+// TestCodecRoundTrip exercises Set/Get through each non-default Codec and
+// each Compressor, since they're otherwise only reachable by an
+// application opting into WithCodec/WithCompressor.
+func TestCodecRoundTrip(t *testing.T) {
+	cases := []struct {
+		name       string
+		codec      Codec
+		compressor Compressor
+	}{
+		{"gob", GobCodec{}, nil},
+		{"cbor", CBORCodec{}, nil},
+		{"json-gzip", JSONCodec{}, GzipCompressor{}},
+		{"gob-zstd", GobCodec{}, ZstdCompressor{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := []Option{WithCodec(c.codec)}
+			if c.compressor != nil {
+				opts = append(opts, WithCompressor(c.compressor))
+			}
+			db, err := Open(t.TempDir(), opts...)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer db.Close()
+
+			if err := db.Set("k", map[string]any{"n": "v"}); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			got, err := db.Get("k")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if _, isCBOR := c.codec.(CBORCodec); isCBOR {
+				// CBORCodec decodes a generic map with interface keys.
+				m, ok := got.(map[any]any)
+				if !ok || m["n"] != "v" {
+					t.Fatalf("Get = %#v, want map[any]any with n=v", got)
+				}
+				return
+			}
+			m, ok := got.(map[string]any)
+			if !ok || m["n"] != "v" {
+				t.Fatalf("Get = %#v, want map with n=v", got)
+			}
+		})
+	}
+}
+
+// TestOpenRejectsCodecMismatch verifies that reopening a store with a
+// different Codec or Compressor than it was initialized with is an error,
+// so documents already on disk never get silently misread.
+func TestOpenRejectsCodecMismatch(t *testing.T) {
+	root := t.TempDir()
+	db, err := Open(root, WithCodec(GobCodec{}))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	db.Close()
+
+	if _, err := Open(root, WithCodec(JSONCodec{})); err == nil {
+		t.Fatalf("Open with mismatched codec: got nil error, want one")
+	}
+	if _, err := Open(root, WithCodec(GobCodec{}), WithCompressor(GzipCompressor{})); err == nil {
+		t.Fatalf("Open with mismatched compressor: got nil error, want one")
+	}
+	if _, err := Open(root, WithCodec(GobCodec{})); err != nil {
+		t.Fatalf("Open with matching codec: got %v, want nil", err)
+	}
+}
+
+// TestRegisterGobType verifies that a custom struct can round-trip through
+// GobCodec once registered with RegisterGobType.
+func TestRegisterGobType(t *testing.T) {
+	type customDoc struct {
+		Name string
+	}
+	RegisterGobType(customDoc{})
+
+	db, err := Open(t.TempDir(), WithCodec(GobCodec{}))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Set("k", customDoc{Name: "x"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, err := db.Get("k")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	cd, ok := got.(customDoc)
+	if !ok || cd.Name != "x" {
+		t.Fatalf("Get = %#v, want customDoc{Name: x}", got)
+	}
+}
@@ -0,0 +1,57 @@
+package filedb
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor wraps a Codec's byte stream with a compression scheme.
+// WithCompressor is optional; without one, documents are stored exactly as
+// their Codec encodes them.
+type Compressor interface {
+	// Name identifies the compressor in .filedb's metadata, the same way
+	// Codec.Name does.
+	Name() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+func compressorName(c Compressor) string {
+	if c == nil {
+		return ""
+	}
+	return c.Name()
+}
+
+// GzipCompressor compresses documents with gzip.
+type GzipCompressor struct{}
+
+func (GzipCompressor) Name() string { return "gzip" }
+
+func (GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (GzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// ZstdCompressor compresses documents with zstd, usually both faster and
+// denser than gzip.
+type ZstdCompressor struct{}
+
+func (ZstdCompressor) Name() string { return "zstd" }
+
+func (ZstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (ZstdCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zr.IOReadCloser(), nil
+}
@@ -1,13 +1,16 @@
 package filedb
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rogpeppe/go-internal/lockedfile"
@@ -15,12 +18,38 @@ import (
 
 // DB is a file-based database.
 type DB struct {
-	root string
+	root       string
+	codec      Codec
+	compressor Compressor // nil means documents are stored uncompressed
+
+	wal   *os.File   // non-nil if opened with WithWAL(true)
+	walMu sync.Mutex // serializes appends and Checkpoint against the shared wal file
+
+	sweepStop chan struct{} // non-nil if opened with WithExpirySweep
+	sweepDone chan struct{}
+
+	watch          atomic.Pointer[watchState] // non-nil once the first Watch call starts it
+	watchStartOnce sync.Once
 }
 
 type dbEntry struct {
-	version int
-	doc     any
+	version   int
+	doc       any
+	expiresAt *time.Time // nil means the entry never expires
+}
+
+// expired reports whether e had already expired as of now.
+func (e dbEntry) expired(now time.Time) bool {
+	return e.expiresAt != nil && !e.expiresAt.After(now)
+}
+
+// dbEntryWire is the exported shape a Codec actually encodes and decodes;
+// dbEntry's own fields are unexported so every codec's default,
+// reflection-based (de)serialization would otherwise see no fields at all.
+type dbEntryWire struct {
+	Version   int        `json:"version"`
+	Doc       any        `json:"doc"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 func keyPath(root string, key string) string {
@@ -41,10 +70,20 @@ func lockPath(root string, key string) string {
 }
 
 // Open opens/initializes a database at the given path.
-func Open(root string) (*DB, error) {
+func Open(root string, opts ...Option) (*DB, error) {
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 
 	initPath := filepath.Join(root, ".filedb")
 
+	codec := o.codec
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
 	ents, err := os.ReadDir(root)
 	if err != nil {
 		return nil, err
@@ -53,30 +92,122 @@ func Open(root string) (*DB, error) {
 		if err := os.Mkdir(initPath, 0600); err != nil {
 			return nil, fmt.Errorf("cannot create .filedb: %w", err)
 		}
+		if err := writeMeta(root, meta{Codec: codec.Name(), Compressor: compressorName(o.compressor)}); err != nil {
+			return nil, fmt.Errorf("cannot write .filedb metadata: %w", err)
+		}
 	} else {
 		if _, err := os.Stat(initPath); err != nil {
 			return nil, errors.New("root not empty and cannot read .filedb")
 		}
+		if err := checkMeta(root, codec, o.compressor); err != nil {
+			return nil, err
+		}
+	}
+
+	db := &DB{root: root, codec: codec, compressor: o.compressor}
+
+	if o.wal {
+		if err := recoverWAL(root, o.recovery, codec, o.compressor); err != nil {
+			return nil, fmt.Errorf("wal recovery: %w", err)
+		}
+		f, err := openWAL(root)
+		if err != nil {
+			return nil, err
+		}
+		db.wal = f
 	}
 
-	return &DB{root}, nil
+	if o.expirySweep > 0 {
+		db.sweepStop = make(chan struct{})
+		db.sweepDone = make(chan struct{})
+		go db.sweepExpired(o.expirySweep)
+	}
+
+	return db, nil
 }
 
-func (db *DB) get(key string) (dbEntry, error) {
-	f, err := os.Open(keyPath(db.root, key))
+// Close stops the background expiry sweeper, if WithExpirySweep was used,
+// stops the Watch backend, if Watch was ever called, and closes the WAL,
+// if WithWAL was used. It does not remove any files.
+func (db *DB) Close() error {
+	if db.sweepStop != nil {
+		close(db.sweepStop)
+		<-db.sweepDone
+	}
+	if ws := db.watch.Load(); ws != nil {
+		close(ws.stop)
+		<-ws.done
+	}
+	if db.wal != nil {
+		return db.wal.Close()
+	}
+	return nil
+}
+
+// readEntryWith reads and decodes the entry at path using the given codec
+// and compressor. It's a free function, rather than a DB method, so WAL
+// recovery can use it before a *DB exists to hang it off of.
+func readEntryWith(path string, codec Codec, compressor Compressor) (dbEntry, error) {
+	f, err := os.Open(path)
 	if err != nil {
 		return dbEntry{}, err
 	}
 	defer f.Close()
 
-	var e dbEntry
-	if err := json.NewDecoder(f).Decode(&e); err != nil {
+	r := io.Reader(f)
+	if compressor != nil {
+		rc, err := compressor.NewReader(f)
+		if err != nil {
+			return dbEntry{}, err
+		}
+		defer rc.Close()
+		r = rc
+	}
+
+	var w dbEntryWire
+	if err := codec.Decode(r, &w); err != nil {
 		return dbEntry{}, err
 	}
-	return e, nil
+	return dbEntry{version: w.Version, doc: w.Doc, expiresAt: w.ExpiresAt}, nil
+}
+
+// writeEntryWith encodes e to f using the given codec and compressor. f is
+// left open and positioned after the written bytes; the caller closes it.
+func writeEntryWith(f *os.File, e dbEntry, codec Codec, compressor Compressor) error {
+	w := io.Writer(f)
+	var wc io.WriteCloser
+	if compressor != nil {
+		var err error
+		wc, err = compressor.NewWriter(f)
+		if err != nil {
+			return err
+		}
+		w = wc
+	}
+	if err := codec.Encode(w, dbEntryWire{e.version, e.doc, e.expiresAt}); err != nil {
+		return err
+	}
+	if wc != nil {
+		return wc.Close()
+	}
+	return nil
+}
+
+func (db *DB) readEntry(path string) (dbEntry, error) {
+	return readEntryWith(path, db.codec, db.compressor)
+}
+
+func (db *DB) writeEntry(f *os.File, e dbEntry) error {
+	return writeEntryWith(f, e, db.codec, db.compressor)
+}
+
+func (db *DB) get(key string) (dbEntry, error) {
+	return db.readEntry(keyPath(db.root, key))
 }
 
 // Get returns the document for the given key, or nil if it does not exist.
+// A key whose TTL (see SetWithTTL) has elapsed is treated as not existing
+// and its file is removed on a best-effort basis.
 func (db *DB) Get(key string) (any, error) {
 	e, err := db.get(key)
 	if err != nil {
@@ -85,23 +216,40 @@ func (db *DB) Get(key string) (any, error) {
 		}
 		return nil, err
 	}
+	if e.expired(time.Now()) {
+		_ = os.Remove(keyPath(db.root, key))
+		return nil, nil
+	}
 	return e.doc, nil
 }
 
-// List returns the keys in the database that have the given prefix.
+// List returns the keys in the database that have the given prefix. Keys
+// whose TTL has elapsed are filtered out.
 func (db *DB) List(prefix string) ([]string, error) {
 	// TODO add prefix santitization (no slashes before or after, no .., etc.)
-	ents, err := os.ReadDir(filepath.Join(db.root, filepath.ToSlash(prefix)))
+	dir := filepath.Join(db.root, filepath.ToSlash(prefix))
+	ents, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	var keys []string
 	for _, ent := range ents {
 		if ent.IsDir() || strings.HasSuffix(ent.Name(), "...tmp") || strings.HasSuffix(ent.Name(), "...lock") {
 			continue
 		}
 		key := ent.Name()
+		e, err := db.readEntry(filepath.Join(dir, key))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		if e.expired(now) {
+			continue
+		}
 		keys = append(keys, key)
 	}
 	return keys, nil
@@ -119,6 +267,33 @@ func (db *DB) SetWithRetry(key string, doc any, retries int) error {
 	return err
 }
 
+// SetWithTTL sets the document for the given key like Set, but the entry
+// expires and is treated as deleted (see Get) once ttl has elapsed.
+func (db *DB) SetWithTTL(key string, doc any, ttl time.Duration) error {
+	exp := time.Now().Add(ttl)
+	_, err := db.update(key, func(_ any) (any, error) { return doc, nil }, 0, &exp)
+	return err
+}
+
+// TTL returns the time remaining before key's document expires, or zero if
+// it has no TTL set or does not exist.
+func (db *DB) TTL(key string) (time.Duration, error) {
+	e, err := db.get(key)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if e.expiresAt == nil {
+		return 0, nil
+	}
+	if d := time.Until(*e.expiresAt); d > 0 {
+		return d, nil
+	}
+	return 0, nil
+}
+
 // Delete deletes the document for the given key.
 func (db *DB) Delete(key string) error {
 	_, err := db.Update(key, func(_ any) (any, error) { return nil, nil }, 0)
@@ -131,8 +306,13 @@ var ErrConcurrentMod = errors.New("concurrent modification")
 // Update updates the document for the given key, using the given function to
 // apply the update. The function will be called with the current document for
 // the key, or nil if the key does not exist. If the function returns nil, the
-// key will be deleted.
+// key will be deleted. Update always clears any TTL previously set on the
+// key via SetWithTTL; use SetWithTTL to set a new one.
 func (db *DB) Update(key string, apply func(existing any) (any, error), retries int) (any, error) {
+	return db.update(key, apply, retries, nil)
+}
+
+func (db *DB) update(key string, apply func(existing any) (any, error), retries int, expiresAt *time.Time) (any, error) {
 
 	do := func() (any, error) {
 		// Get the current doc for its version. Non-existent files will end with
@@ -152,9 +332,25 @@ func (db *DB) Update(key string, apply func(existing any) (any, error), retries
 			return nil, err
 		}
 		if newDoc == nil {
-			if err := os.Remove(keyPath(db.root, key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			if db.wal != nil {
+				// Hold walMu across both the WAL append and the remove it
+				// protects, so a Checkpoint can't truncate this record out
+				// from under an in-flight delete between the two.
+				db.walMu.Lock()
+				err := appendWAL(db.wal, walRecord{Key: key, Version: old.version + 1, Tomb: true})
+				if err != nil {
+					db.walMu.Unlock()
+					return nil, err
+				}
+				rmErr := os.Remove(keyPath(db.root, key))
+				db.walMu.Unlock()
+				if rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+					return nil, fmt.Errorf("failed to remove key '%s': %w", key, rmErr)
+				}
+			} else if err := os.Remove(keyPath(db.root, key)); err != nil && !errors.Is(err, os.ErrNotExist) {
 				return nil, fmt.Errorf("failed to remove key '%s': %w", key, err)
 			}
+			db.notify(Event{Key: key, OldVersion: old.version, Kind: EventDelete})
 			return nil, nil
 		}
 
@@ -165,7 +361,7 @@ func (db *DB) Update(key string, apply func(existing any) (any, error), retries
 		defer f.Close()
 		defer os.Remove(f.Name())
 
-		if err := json.NewEncoder(f).Encode(dbEntry{old.version + 1, newDoc}); err != nil {
+		if err := db.writeEntry(f, dbEntry{old.version + 1, newDoc, expiresAt}); err != nil {
 			return nil, err
 		}
 		if err := f.Close(); err != nil {
@@ -190,10 +386,36 @@ func (db *DB) Update(key string, apply func(existing any) (any, error), retries
 			return nil, ErrConcurrentMod
 		}
 
-		return newDoc, os.Rename(f.Name(), keyPath(db.root, key))
+		// Record the mutation in the WAL before the rename that makes it
+		// visible, so a crash between the two can be recovered from. walMu
+		// is held across both, not just the append, so a concurrent
+		// Checkpoint can't truncate this record out from under the rename
+		// it's meant to protect.
+
+		if db.wal != nil {
+			var buf bytes.Buffer
+			if err := db.codec.Encode(&buf, newDoc); err != nil {
+				return nil, err
+			}
+			db.walMu.Lock()
+			err := appendWAL(db.wal, walRecord{Key: key, Version: old.version + 1, Doc: buf.Bytes(), ExpiresAt: expiresAt})
+			if err != nil {
+				db.walMu.Unlock()
+				return nil, err
+			}
+			renameErr := os.Rename(f.Name(), keyPath(db.root, key))
+			db.walMu.Unlock()
+			if renameErr != nil {
+				return nil, renameErr
+			}
+		} else if err := os.Rename(f.Name(), keyPath(db.root, key)); err != nil {
+			return nil, err
+		}
+		db.notify(Event{Key: key, OldVersion: old.version, NewVersion: old.version + 1, Kind: EventPut})
+		return newDoc, nil
 	}
 
-	for i := 0; retries < 0 || i < retries; i++ {
+	for i := 0; retries < 0 || i <= retries; i++ {
 		doc, err := do()
 		if err == nil || err != ErrConcurrentMod {
 			return doc, err
@@ -0,0 +1,162 @@
+package filedb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Iterator walks the keys under a prefix depth first, visiting each
+// directory's entries in name order, and reads each document on demand
+// rather than loading the full key set into memory the way List does.
+// This is lexicographic order of the keys' slash-separated components; it
+// differs from plain string order where a name holds a character that
+// sorts before '/', such as '-' or '.'. The zero value is not usable;
+// construct one with DB.Iterator or Snapshot.Iterator.
+type Iterator struct {
+	db     *DB // supplies the codec/compressor to read documents with
+	root   string
+	prefix string
+
+	init  bool
+	seek  *string
+	stack []iterFrame
+	key   string
+	val   any
+	err   error
+}
+
+type iterFrame struct {
+	path string // slash-separated path of this directory, relative to root
+	ents []os.DirEntry
+	i    int
+}
+
+// Iterator returns an Iterator over the live keys in db with the given
+// prefix.
+func (db *DB) Iterator(prefix string) *Iterator {
+	return &Iterator{db: db, root: db.root, prefix: prefix}
+}
+
+// Iterator returns an Iterator over the keys with the given prefix as they
+// existed at the time the snapshot was taken.
+func (s *Snapshot) Iterator(prefix string) *Iterator {
+	return &Iterator{db: s.db, root: s.dir, prefix: prefix}
+}
+
+func (it *Iterator) reset() {
+	it.init = true
+	it.key, it.val, it.err = "", nil, nil
+	dir := filepath.ToSlash(it.prefix)
+	ents, err := os.ReadDir(filepath.Join(it.root, dir))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			it.err = err
+		}
+		it.stack = nil
+		return
+	}
+	it.stack = []iterFrame{{path: dir, ents: ents}}
+}
+
+// advance walks to the next document under the prefix, in iteration order
+// (see Iterator), ignoring any Seek target.
+func (it *Iterator) advance() bool {
+	for len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		if top.i >= len(top.ents) {
+			it.stack = it.stack[:len(it.stack)-1]
+			continue
+		}
+		ent := top.ents[top.i]
+		top.i++
+
+		name := ent.Name()
+		if strings.Contains(name, "...tmp.") || strings.HasSuffix(name, "...lock") {
+			continue
+		}
+		if top.path == "" && name == ".filedb" {
+			continue
+		}
+		key := name
+		if top.path != "" {
+			key = top.path + "/" + name
+		}
+
+		if ent.IsDir() {
+			ents, err := os.ReadDir(filepath.Join(it.root, key))
+			if err != nil {
+				it.err = err
+				return false
+			}
+			it.stack = append(it.stack, iterFrame{path: key, ents: ents})
+			continue
+		}
+
+		e, err := it.db.readEntry(filepath.Join(it.root, key))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// Raced with a concurrent delete of a live key; skip it.
+				continue
+			}
+			it.err = err
+			return false
+		}
+		if e.expired(time.Now()) {
+			continue
+		}
+		it.key, it.val = key, e.doc
+		return true
+	}
+	return false
+}
+
+// Seek positions the iterator so that the next call to Next advances to
+// the first key, in iteration order, that is greater than or equal to key
+// as a string. Keys after that one are returned without being compared
+// against key.
+func (it *Iterator) Seek(key string) {
+	it.reset()
+	it.seek = &key
+}
+
+// Next advances the iterator to the next key and reports whether one was
+// found. It must be called before the first Key/Value.
+func (it *Iterator) Next() bool {
+	if !it.init {
+		it.reset()
+	}
+	if it.err != nil {
+		return false
+	}
+	for it.advance() {
+		if it.seek != nil && it.key < *it.seek {
+			continue
+		}
+		it.seek = nil
+		return true
+	}
+	return false
+}
+
+// Key returns the key at the iterator's current position.
+func (it *Iterator) Key() string {
+	return it.key
+}
+
+// Value returns the document at the iterator's current position.
+func (it *Iterator) Value() any {
+	return it.val
+}
+
+// Err returns the first error, if any, encountered while iterating.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close releases any resources held by the iterator.
+func (it *Iterator) Close() error {
+	return nil
+}
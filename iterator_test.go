@@ -0,0 +1,51 @@
+package filedb
+
+import (
+	"testing"
+	"time"
+)
+
+// TestIteratorSkipsFiledbDir iterates an empty prefix (i.e. the whole root)
+// with the WAL, expiry sweeper and watcher all enabled, so that .filedb
+// holds a meta file and a wal file and the root holds the keys' lock files
+// alongside the real keys. The iterator must skip .filedb entirely instead
+// of trying to decode its contents as documents.
+func TestIteratorSkipsFiledbDir(t *testing.T) {
+	db, err := Open(t.TempDir(), WithWAL(true), WithExpirySweep(time.Hour))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	if _, _, err := db.Watch(""); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	if err := db.Set("a", "first"); err != nil {
+		t.Fatalf("Set(a): %v", err)
+	}
+	if err := db.Set("b", "second"); err != nil {
+		t.Fatalf("Set(b): %v", err)
+	}
+
+	it := db.Iterator("")
+	defer it.Close()
+
+	got := map[string]any{}
+	for it.Next() {
+		got[it.Key()] = it.Value()
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iterate: %v", err)
+	}
+
+	want := map[string]any{"a": "first", "b": "second"}
+	if len(got) != len(want) {
+		t.Fatalf("got keys %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("key %q: got %v, want %v", k, got[k], v)
+		}
+	}
+}
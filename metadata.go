@@ -0,0 +1,66 @@
+package filedb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// meta records the codec and compressor a store was initialized with.
+// It's always encoded as plain JSON, independent of the store's own
+// configured Codec, since it has to be readable before that's known.
+type meta struct {
+	Codec      string `json:"codec"`
+	Compressor string `json:"compressor,omitempty"`
+}
+
+func metaPath(root string) string {
+	return filepath.Join(root, ".filedb", "meta")
+}
+
+func writeMeta(root string, m meta) error {
+	f, err := os.OpenFile(metaPath(root), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(m)
+}
+
+func readMeta(root string) (meta, error) {
+	f, err := os.Open(metaPath(root))
+	if err != nil {
+		return meta{}, err
+	}
+	defer f.Close()
+
+	var m meta
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return meta{}, err
+	}
+	return m, nil
+}
+
+// checkMeta verifies that an already-initialized store's codec and
+// compressor match the ones it's being opened with, so documents already
+// on disk never get silently misread by the wrong codec.
+func checkMeta(root string, codec Codec, compressor Compressor) error {
+	m, err := readMeta(root)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		// Pre-dates metadata tracking: every store used to be JSON with no
+		// compression, so that's what an absent meta file means.
+		m = meta{Codec: JSONCodec{}.Name()}
+	}
+
+	wantCompressor := compressorName(compressor)
+	if m.Codec != codec.Name() || m.Compressor != wantCompressor {
+		return fmt.Errorf("filedb: store was initialized with codec %q/compressor %q, cannot open with codec %q/compressor %q",
+			m.Codec, m.Compressor, codec.Name(), wantCompressor)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package filedb
+
+import "time"
+
+// Option configures optional behavior of Open.
+type Option func(*options)
+
+type options struct {
+	wal         bool
+	recovery    bool
+	expirySweep time.Duration
+	codec       Codec
+	compressor  Compressor
+}
+
+// WithWAL enables an append-only write-ahead log under .filedb/wal that
+// makes Update durable across a crash between the WAL write and the
+// tmp-file rename it protects, at the cost of an extra fsync per write.
+func WithWAL(enabled bool) Option {
+	return func(o *options) { o.wal = enabled }
+}
+
+// WithRecovery controls how Open handles a WAL left truncated by a crash
+// mid-append. By default a truncated final record is reported as an
+// error; with WithRecovery(true), Open recovers as much of the log as it
+// can and discards the rest.
+func WithRecovery(enabled bool) Option {
+	return func(o *options) { o.recovery = enabled }
+}
+
+// WithExpirySweep starts a background goroutine, stopped by DB.Close, that
+// walks the tree every interval removing documents set with SetWithTTL
+// whose TTL has elapsed. It takes the same per-key lock as Update so it
+// never races with an in-flight writer. Without it, an expired document is
+// only ever removed lazily, the next time Get happens to read it.
+func WithExpirySweep(interval time.Duration) Option {
+	return func(o *options) { o.expirySweep = interval }
+}
+
+// WithCodec sets the Codec used to encode and decode documents, in place
+// of the default JSONCodec. The chosen codec is recorded in .filedb's
+// metadata at initialization; opening the same store with a different one
+// later is an error.
+func WithCodec(c Codec) Option {
+	return func(o *options) { o.codec = c }
+}
+
+// WithCompressor wraps the configured Codec's byte stream in a compression
+// scheme. There is no compression by default. Like the codec, it's
+// recorded in .filedb's metadata and can't be changed on reopen.
+func WithCompressor(c Compressor) Option {
+	return func(o *options) { o.compressor = c }
+}
@@ -0,0 +1,99 @@
+package filedb
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Snapshot is a stable, point-in-time view of the documents in a DB.
+// Concurrent Set, Delete, Update and Write calls against the DB never
+// affect reads made through a Snapshot. Callers should Close a Snapshot
+// once they're done with it to release the disk space it holds onto.
+type Snapshot struct {
+	db  *DB
+	dir string
+}
+
+// Snapshot captures the current state of every document in db. It does so
+// by hard-linking each file into a private directory, so the snapshot
+// keeps seeing a key's old content even after it's been overwritten or
+// deleted in the live DB.
+func (db *DB) Snapshot() (*Snapshot, error) {
+
+	base := filepath.Join(db.root, ".filedb", "snapshots")
+	if err := os.MkdirAll(base, 0700); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(base, "")
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(db.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(db.root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if rel == ".filedb" || strings.HasPrefix(rel, ".filedb/") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.Contains(rel, "...tmp.") || strings.HasSuffix(rel, "...lock") {
+			return nil
+		}
+
+		dst := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+			return err
+		}
+		if err := os.Link(path, dst); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// Raced with a concurrent delete of a live key; there's
+				// nothing to snapshot.
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return &Snapshot{db: db, dir: dir}, nil
+}
+
+// Get returns the document for the given key as it existed when the
+// snapshot was taken, or nil if it did not exist.
+func (s *Snapshot) Get(key string) (any, error) {
+	e, err := s.db.readEntry(keyPath(s.dir, key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return e.doc, nil
+}
+
+// Close releases the disk space held by the snapshot. The snapshot and any
+// Iterator obtained from it must not be used afterwards.
+func (s *Snapshot) Close() error {
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,75 @@
+package filedb
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rogpeppe/go-internal/lockedfile"
+)
+
+// sweepExpired runs until db.sweepStop is closed, removing expired
+// documents from the tree every interval.
+func (db *DB) sweepExpired(interval time.Duration) {
+	defer close(db.sweepDone)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-db.sweepStop:
+			return
+		case <-t.C:
+			db.sweepOnce()
+		}
+	}
+}
+
+// sweepOnce walks the tree once, removing any document whose TTL has
+// elapsed.
+func (db *DB) sweepOnce() {
+	filepath.WalkDir(db.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".filedb" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if strings.Contains(name, "...tmp.") || strings.HasSuffix(name, "...lock") {
+			return nil
+		}
+		rel, err := filepath.Rel(db.root, path)
+		if err != nil {
+			return err
+		}
+		db.sweepKey(filepath.ToSlash(rel))
+		return nil
+	})
+}
+
+// sweepKey removes key's file if it has expired, taking the same per-key
+// lock as Update so it never races with an in-flight writer.
+func (db *DB) sweepKey(key string) {
+	unlock, err := lockedfile.MutexAt(lockPath(db.root, key)).Lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	e, err := db.get(key)
+	if err != nil {
+		return
+	}
+	if e.expired(time.Now()) {
+		if os.Remove(keyPath(db.root, key)) == nil {
+			db.notify(Event{Key: key, OldVersion: e.version, Kind: EventDelete})
+		}
+	}
+}
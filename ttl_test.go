@@ -0,0 +1,64 @@
+package filedb
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestSetWithTTLExpires verifies that a document set with SetWithTTL is
+// readable until its TTL elapses, then treated as deleted by both Get and
+// TTL, the same as a key that was never written.
+func TestSetWithTTLExpires(t *testing.T) {
+	db, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetWithTTL("k", "v", 30*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL: %v", err)
+	}
+
+	got, err := db.Get("k")
+	if err != nil || got != "v" {
+		t.Fatalf("Get before expiry: got (%v, %v), want (v, nil)", got, err)
+	}
+	if ttl, err := db.TTL("k"); err != nil || ttl <= 0 {
+		t.Fatalf("TTL before expiry: got (%v, %v), want > 0", ttl, err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if got, err := db.Get("k"); err != nil || got != nil {
+		t.Fatalf("Get after expiry: got (%v, %v), want (nil, nil)", got, err)
+	}
+	if ttl, err := db.TTL("k"); err != nil || ttl != 0 {
+		t.Fatalf("TTL after expiry: got (%v, %v), want (0, nil)", ttl, err)
+	}
+}
+
+// TestExpirySweepRemovesFile verifies that WithExpirySweep actually removes
+// an expired key's file on disk in the background, rather than only hiding
+// it lazily the next time Get happens to read it.
+func TestExpirySweepRemovesFile(t *testing.T) {
+	root := t.TempDir()
+	db, err := Open(root, WithExpirySweep(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetWithTTL("k", "v", 10*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(keyPath(root, "k")); os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("key file for an expired key was not swept within the deadline")
+}
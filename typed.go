@@ -0,0 +1,120 @@
+package filedb
+
+import (
+	"bytes"
+	"reflect"
+)
+
+// TypedDB is a typed view over a DB that decodes documents directly into T
+// instead of leaving callers to re-marshal the map[string]any that Get
+// otherwise returns.
+type TypedDB[T any] struct {
+	db *DB
+}
+
+// NewTypedDB returns a TypedDB[T] backed by db. Documents are still stored
+// under db using its own configured Codec/Compressor, so an untyped DB and
+// any number of differently typed TypedDB wrappers can share the same root
+// safely.
+func NewTypedDB[T any](db *DB) *TypedDB[T] {
+	return &TypedDB[T]{db: db}
+}
+
+// decodeAs re-encodes doc (as decoded generically by db's own Codec) and
+// decodes it back into a T, through that same Codec. Routing through
+// db.codec rather than a hardcoded encoding/json round trip matters once
+// WithCodec is in play: CBORCodec, for instance, decodes generic documents
+// into map[any]any, which json.Marshal can't handle at all.
+func decodeAs[T any](codec Codec, doc any) (T, error) {
+	var v T
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, doc); err != nil {
+		return v, err
+	}
+	if err := codec.Decode(&buf, &v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// isNilT reports whether v is a nil pointer, map, slice, chan, func or
+// interface. DB.Update treats an apply result of any(nil) as "delete this
+// key", but wrapping a nil *T (or other nilable T) in an any doesn't
+// produce any(nil) — it produces a non-nil interface holding a nil
+// pointer — so TypedDB.Update must detect this itself before forwarding
+// to DB.Update, or a "delete" from a pointer-typed apply would instead
+// write a literal null document.
+func isNilT[T any](v T) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Map, reflect.Ptr, reflect.Slice, reflect.Interface, reflect.UnsafePointer:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+// Get returns the document for key decoded into T, and whether it existed.
+func (t *TypedDB[T]) Get(key string) (T, bool, error) {
+	var zero T
+	doc, err := t.db.Get(key)
+	if err != nil {
+		return zero, false, err
+	}
+	if doc == nil {
+		return zero, false, nil
+	}
+	v, err := decodeAs[T](t.db.codec, doc)
+	if err != nil {
+		return zero, false, err
+	}
+	return v, true, nil
+}
+
+// Set sets the document for key to doc.
+func (t *TypedDB[T]) Set(key string, doc T) error {
+	return t.db.Set(key, doc)
+}
+
+// Delete deletes the document for key.
+func (t *TypedDB[T]) Delete(key string) error {
+	return t.db.Delete(key)
+}
+
+// Update updates the document for key, using apply to compute the new
+// value from the current one (the zero value of T if the key does not
+// exist). Returning a nil T (for a pointer, map, slice, chan or func T)
+// deletes the key, the same as DB.Update's apply returning a nil any. Like
+// DB.Update, it retries up to the given number of times if the key is
+// concurrently modified; a negative retries retries forever.
+func (t *TypedDB[T]) Update(key string, apply func(existing T) (T, error), retries int) (T, error) {
+	var zero T
+	res, err := t.db.Update(key, func(existing any) (any, error) {
+		cur := zero
+		if existing != nil {
+			v, err := decodeAs[T](t.db.codec, existing)
+			if err != nil {
+				return nil, err
+			}
+			cur = v
+		}
+		newDoc, err := apply(cur)
+		if err != nil {
+			return nil, err
+		}
+		if isNilT(newDoc) {
+			return nil, nil
+		}
+		return newDoc, nil
+	}, retries)
+	if err != nil {
+		return zero, err
+	}
+	if res == nil {
+		return zero, nil
+	}
+	return decodeAs[T](t.db.codec, res)
+}
@@ -0,0 +1,72 @@
+package filedb
+
+import "testing"
+
+type widget struct {
+	Name string
+}
+
+// TestTypedDBUpdateBasic exercises the common Get/Set/Update/Delete paths
+// through a pointer-typed TypedDB.
+func TestTypedDBUpdateBasic(t *testing.T) {
+	db, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	td := NewTypedDB[*widget](db)
+
+	if err := td.Set("w", &widget{Name: "a"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, ok, err := td.Get("w")
+	if err != nil || !ok || got.Name != "a" {
+		t.Fatalf("Get after Set: got (%v, %v, %v)", got, ok, err)
+	}
+
+	if _, err := td.Update("w", func(w *widget) (*widget, error) {
+		w.Name = "b"
+		return w, nil
+	}, 0); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, ok, err = td.Get("w")
+	if err != nil || !ok || got.Name != "b" {
+		t.Fatalf("Get after Update: got (%v, %v, %v)", got, ok, err)
+	}
+}
+
+// TestTypedDBUpdateNilDeletes verifies that an Update whose apply returns a
+// nil *widget deletes the key outright, rather than writing a literal null
+// document that Get reports as missing but List still returns.
+func TestTypedDBUpdateNilDeletes(t *testing.T) {
+	db, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	td := NewTypedDB[*widget](db)
+	if err := td.Set("w", &widget{Name: "a"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	if _, err := td.Update("w", func(*widget) (*widget, error) {
+		return nil, nil
+	}, 0); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if got, ok, err := td.Get("w"); err != nil || ok {
+		t.Fatalf("Get after deleting Update: got (%v, %v, %v), want not found", got, ok, err)
+	}
+
+	keys, err := db.List("")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("List after deleting Update: got %v, want empty (zombie entry left behind)", keys)
+	}
+}
@@ -0,0 +1,185 @@
+package filedb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// walRecord is one mutation framed into the write-ahead log. The log's own
+// framing is always JSON, independent of the DB's configured Codec; Doc
+// holds the document encoded with that Codec (uncompressed), which
+// encoding/json transparently base64s as part of the surrounding record.
+type walRecord struct {
+	Key       string     `json:"key"`
+	Version   int        `json:"version"`
+	Tomb      bool       `json:"tomb,omitempty"`
+	Doc       []byte     `json:"doc,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+func walPath(root string) string {
+	return filepath.Join(root, ".filedb", "wal")
+}
+
+func openWAL(root string) (*os.File, error) {
+	return os.OpenFile(walPath(root), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+}
+
+// appendWAL appends rec to the WAL, framed with a length prefix and CRC,
+// and fsyncs it before returning.
+func appendWAL(f *os.File, rec walRecord) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	var hdr [8]byte
+	binary.BigEndian.PutUint32(hdr[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(hdr[4:8], crc32.ChecksumIEEE(payload))
+	if _, err := f.Write(hdr[:]); err != nil {
+		return err
+	}
+	if _, err := f.Write(payload); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// readWAL reads every well-formed record from the WAL in order. A WAL
+// truncated mid-append by a crash ends in a partial frame: with recovery
+// set, readWAL stops there and returns everything read so far instead of
+// failing on the resulting io.ErrUnexpectedEOF; without it, that partial
+// frame is reported as an error.
+func readWAL(root string, recovery bool) ([]walRecord, error) {
+	f, err := os.Open(walPath(root))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var recs []walRecord
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if recovery && errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, err
+		}
+
+		n := binary.BigEndian.Uint32(hdr[0:4])
+		wantCRC := binary.BigEndian.Uint32(hdr[4:8])
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			if recovery && errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(payload) != wantCRC {
+			if recovery {
+				break
+			}
+			return nil, errors.New("filedb: corrupt wal record (checksum mismatch)")
+		}
+
+		var rec walRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			if recovery {
+				break
+			}
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// recoverWAL replays WAL records whose target file is missing or older
+// than the record's version, then truncates the WAL. It is called once,
+// from Open, before the WAL is reopened for appending.
+func recoverWAL(root string, recovery bool, codec Codec, compressor Compressor) error {
+	recs, err := readWAL(root, recovery)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range recs {
+		cur, err := readEntryWith(keyPath(root, rec.Key), codec, compressor)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if cur.version >= rec.Version {
+			continue // already durable on disk
+		}
+
+		if rec.Tomb {
+			if err := os.Remove(keyPath(root, rec.Key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+
+		var doc any
+		if err := codec.Decode(bytes.NewReader(rec.Doc), &doc); err != nil {
+			return err
+		}
+		f, err := tmpFile(root, rec.Key)
+		if err != nil {
+			return err
+		}
+		err = writeEntryWith(f, dbEntry{rec.Version, doc, rec.ExpiresAt}, codec, compressor)
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+			return err
+		}
+		if err := os.Rename(f.Name(), keyPath(root, rec.Key)); err != nil {
+			return err
+		}
+	}
+
+	if len(recs) == 0 {
+		return nil
+	}
+	return os.Truncate(walPath(root), 0)
+}
+
+// Checkpoint fsyncs the write-ahead log and then truncates it, discarding
+// every record in it. It does not fsync the target files those records'
+// mutations were renamed into. It is a no-op if the WAL was not enabled via
+// WithWAL. It holds walMu, which Update and Write hold across appendWAL and
+// the rename/remove it protects, so it can never truncate away a record an
+// in-flight Update or Write has appended but not yet applied.
+func (db *DB) Checkpoint() error {
+	if db.wal == nil {
+		return nil
+	}
+	db.walMu.Lock()
+	defer db.walMu.Unlock()
+	if err := db.wal.Sync(); err != nil {
+		return fmt.Errorf("filedb: checkpoint: %w", err)
+	}
+	if err := db.wal.Truncate(0); err != nil {
+		return fmt.Errorf("filedb: checkpoint: %w", err)
+	}
+	if _, err := db.wal.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("filedb: checkpoint: %w", err)
+	}
+	return nil
+}
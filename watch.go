@@ -0,0 +1,320 @@
+package filedb
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// EventKind identifies what kind of change an Event describes.
+type EventKind int
+
+const (
+	// EventPut means the key was created or its document was replaced.
+	EventPut EventKind = iota
+	// EventDelete means the key's document was removed, including by
+	// TTL expiration.
+	EventDelete
+)
+
+// Event describes a single change to a key observed by Watch.
+type Event struct {
+	Key        string
+	OldVersion int
+	NewVersion int // zero for EventDelete
+	Kind       EventKind
+}
+
+// watcher is one Watch call's subscription.
+type watcher struct {
+	prefix string
+	ch     chan Event
+}
+
+// watchState is the shared backend started lazily by the first call to
+// Watch. It fans out Events to every watcher whose prefix matches, and,
+// for the fsnotify/polling fallback path, tracks the last known version
+// of every key so a bare filesystem change can be turned into an Event
+// with the right OldVersion/NewVersion.
+type watchState struct {
+	db *DB
+
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+	versions map[string]int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Watch subscribes to changes to keys with the given prefix, returning a
+// channel of Events and a function to unsubscribe and release it. The
+// channel is not closed by unsubscribing; the caller must stop reading
+// from it once it calls the returned function. Events are delivered on a
+// best-effort basis: a slow consumer can miss some.
+//
+// Watch observes both changes made through this *DB (Set, Update, Delete,
+// Batch, TTL expiration) and, when backed by fsnotify, changes made by
+// other processes sharing the same root.
+func (db *DB) Watch(prefix string) (<-chan Event, func(), error) {
+	ws, err := db.watchBackend()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := &watcher{prefix: prefix, ch: make(chan Event, 16)}
+	ws.mu.Lock()
+	ws.watchers[w] = struct{}{}
+	ws.mu.Unlock()
+
+	cancel := func() {
+		ws.mu.Lock()
+		delete(ws.watchers, w)
+		ws.mu.Unlock()
+	}
+	return w.ch, cancel, nil
+}
+
+// watchBackend returns db's watchState, starting it on the first call.
+func (db *DB) watchBackend() (*watchState, error) {
+	var startErr error
+	db.watchStartOnce.Do(func() {
+		ws := &watchState{
+			db:       db,
+			watchers: make(map[*watcher]struct{}),
+			versions: make(map[string]int),
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+		seedWatchVersions(ws)
+
+		if fw, err := fsnotify.NewWatcher(); err == nil {
+			if err := addWatchDirs(fw, db.root); err != nil {
+				fw.Close()
+				startErr = err
+				return
+			}
+			go watchFsnotify(fw, ws)
+		} else {
+			go pollForChanges(ws)
+		}
+		db.watch.Store(ws)
+	})
+	if startErr != nil {
+		return nil, startErr
+	}
+	return db.watch.Load(), nil
+}
+
+// seedWatchVersions records the version of every key already on disk, so
+// the backend's first look at the tree doesn't mistake pre-existing keys
+// for changes.
+func seedWatchVersions(ws *watchState) {
+	filepath.WalkDir(ws.db.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".filedb" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isWatchedFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(ws.db.root, path)
+		if err != nil {
+			return nil
+		}
+		e, err := ws.db.readEntry(path)
+		if err != nil {
+			return nil
+		}
+		ws.versions[filepath.ToSlash(rel)] = e.version
+		return nil
+	})
+}
+
+// notify delivers ev to every watcher whose prefix matches, if the watch
+// backend has ever been started. It never blocks on a slow consumer.
+func (db *DB) notify(ev Event) {
+	ws := db.watch.Load()
+	if ws == nil {
+		return
+	}
+	ws.mu.Lock()
+	if ev.Kind == EventDelete {
+		delete(ws.versions, ev.Key)
+	} else {
+		ws.versions[ev.Key] = ev.NewVersion
+	}
+	watchers := make([]*watcher, 0, len(ws.watchers))
+	for w := range ws.watchers {
+		watchers = append(watchers, w)
+	}
+	ws.mu.Unlock()
+
+	for _, w := range watchers {
+		if !strings.HasPrefix(ev.Key, w.prefix) {
+			continue
+		}
+		select {
+		case w.ch <- ev:
+		default:
+		}
+	}
+}
+
+func isWatchedFile(name string) bool {
+	return !strings.Contains(name, "...tmp.") && !strings.HasSuffix(name, "...lock")
+}
+
+// addWatchDirs registers every directory under root, except .filedb, with
+// fw. fsnotify watches directories, not trees, so new subdirectories are
+// picked up as they're reported.
+func addWatchDirs(fw *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".filedb" {
+			return filepath.SkipDir
+		}
+		return fw.Add(path)
+	})
+}
+
+// watchFsnotify runs until ws.stop is closed, translating raw fsnotify
+// events into Events via syncKeyVersion.
+func watchFsnotify(fw *fsnotify.Watcher, ws *watchState) {
+	defer close(ws.done)
+	defer fw.Close()
+
+	for {
+		select {
+		case <-ws.stop:
+			return
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return
+			}
+			if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+				fw.Add(ev.Name)
+				continue
+			}
+			handleFsEvent(ws, ev.Name)
+		case <-fw.Errors:
+			// Best-effort: a watch error doesn't stop the backend.
+		}
+	}
+}
+
+// handleFsEvent resolves a raw filesystem path into a key and brings
+// ws.versions, and thus any subscribed watchers, up to date with it.
+func handleFsEvent(ws *watchState, path string) {
+	rel, err := filepath.Rel(ws.db.root, path)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".filedb" || strings.HasPrefix(rel, ".filedb/") {
+		return
+	}
+	if !isWatchedFile(filepath.Base(rel)) {
+		return
+	}
+	syncKeyVersion(ws, rel)
+}
+
+// syncKeyVersion is the single source of truth for turning an observed
+// change to key's file into at most one Event, by comparing the file's
+// current version against the last one ws saw.
+func syncKeyVersion(ws *watchState, key string) {
+	e, err := ws.db.get(key)
+
+	ws.mu.Lock()
+	old, existed := ws.versions[key]
+	ws.mu.Unlock()
+
+	if err != nil {
+		if existed {
+			ws.mu.Lock()
+			delete(ws.versions, key)
+			ws.mu.Unlock()
+			ws.db.notify(Event{Key: key, OldVersion: old, Kind: EventDelete})
+		}
+		return
+	}
+	if e.version == old {
+		return
+	}
+	ws.db.notify(Event{Key: key, OldVersion: old, NewVersion: e.version, Kind: EventPut})
+}
+
+// pollForChanges is the fallback backend used when fsnotify isn't
+// available, walking the tree every interval to diff observed versions
+// against ws.versions.
+func pollForChanges(ws *watchState) {
+	defer close(ws.done)
+
+	t := time.NewTicker(500 * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ws.stop:
+			return
+		case <-t.C:
+			pollOnce(ws)
+		}
+	}
+}
+
+func pollOnce(ws *watchState) {
+	seen := make(map[string]struct{})
+
+	filepath.WalkDir(ws.db.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".filedb" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isWatchedFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(ws.db.root, path)
+		if err != nil {
+			return nil
+		}
+		key := filepath.ToSlash(rel)
+		seen[key] = struct{}{}
+		syncKeyVersion(ws, key)
+		return nil
+	})
+
+	ws.mu.Lock()
+	var gone []string
+	for key := range ws.versions {
+		if _, ok := seen[key]; !ok {
+			gone = append(gone, key)
+		}
+	}
+	ws.mu.Unlock()
+
+	for _, key := range gone {
+		syncKeyVersion(ws, key)
+	}
+}
@@ -0,0 +1,64 @@
+package filedb
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWatchReceivesEvents verifies that Watch delivers a Put event for a Set
+// and a Delete event for a Delete, and that a watcher subscribed to an
+// unrelated prefix sees neither.
+func TestWatchReceivesEvents(t *testing.T) {
+	db, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	ch, cancel, err := db.Watch("a")
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer cancel()
+
+	other, cancelOther, err := db.Watch("zzz")
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer cancelOther()
+
+	if err := db.Set("a1", "v"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	ev := recvEvent(t, ch)
+	if ev.Key != "a1" || ev.Kind != EventPut || ev.NewVersion != 1 {
+		t.Fatalf("Put event: got %+v", ev)
+	}
+
+	if err := db.Delete("a1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	ev = recvEvent(t, ch)
+	if ev.Key != "a1" || ev.Kind != EventDelete || ev.OldVersion != 1 {
+		t.Fatalf("Delete event: got %+v", ev)
+	}
+
+	select {
+	case ev := <-other:
+		t.Fatalf("watcher on unrelated prefix received event: %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func recvEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for event")
+		return Event{}
+	}
+}